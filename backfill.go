@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// backfillChunkSize is the max block range per FilterLogs call, chosen to stay comfortably under
+// the range limits providers like Infura enforce.
+const backfillChunkSize = uint64(10_000)
+
+// backfillRange returns the inclusive block range that should be replayed on startup for pools,
+// and whether there is anything to replay at all. replayFrom, when set, overrides the per-pool
+// checkpoints (manual --replay-from re-run); otherwise the range starts just after the earliest
+// checkpoint recorded across pools, so pools with no checkpoint yet are left to the live
+// subscription rather than replayed from genesis.
+func backfillRange(pools []PoolInfo, cp *checkpoint, minConfirmations, head uint64, replayFrom *uint64) (fromBlock, toBlock uint64, ok bool) {
+	if head < minConfirmations {
+		return 0, 0, false
+	}
+	toBlock = head - minConfirmations
+
+	if replayFrom != nil {
+		fromBlock = *replayFrom
+		return fromBlock, toBlock, fromBlock <= toBlock
+	}
+
+	found := false
+	for _, pool := range pools {
+		last, has := cp.get(pool.Address)
+		if !has {
+			continue
+		}
+		candidate := last + 1
+		if !found || candidate < fromBlock {
+			fromBlock = candidate
+			found = true
+		}
+	}
+	if !found {
+		return 0, 0, false
+	}
+	return fromBlock, toBlock, fromBlock <= toBlock
+}
+
+// runBackfill replays AllocateStablecoins events between fromBlock and toBlock (inclusive) for a
+// single endpoint's pools, chunked to respect provider block-range limits, through the same
+// parsing/notification path the live subscription uses, marking each message as [backfill]. The
+// checkpoint is updated and persisted after every successful send so a crash mid-backfill resumes
+// instead of repeating. With ignoreCheckpoint set (a manual --replay-from re-run), every event in
+// the range is replayed, including ones a previous run already notified on.
+func runBackfill(ctx context.Context, client *ethclient.Client, contractABI abi.ABI, bot *tgbotapi.BotAPI, chatID string, cp *checkpoint, checkpointPath string, pools []PoolInfo, allocateHash common.Hash, fromBlock, toBlock uint64, ignoreCheckpoint bool) error {
+	log.Printf("⏪ Backfilling AllocateStablecoins events from block %d to %d", fromBlock, toBlock)
+
+	contractAddresses := make([]common.Address, 0, len(pools))
+	poolsByAddress := buildPoolInfoByAddress(pools)
+	for _, pool := range pools {
+		contractAddresses = append(contractAddresses, pool.Address)
+	}
+
+	for chunkStart := fromBlock; chunkStart <= toBlock; chunkStart += backfillChunkSize {
+		chunkEnd := chunkStart + backfillChunkSize - 1
+		if chunkEnd > toBlock {
+			chunkEnd = toBlock
+		}
+
+		query := ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(chunkStart),
+			ToBlock:   new(big.Int).SetUint64(chunkEnd),
+			Addresses: contractAddresses,
+			Topics:    [][]common.Hash{{allocateHash}},
+		}
+
+		logs, err := client.FilterLogs(ctx, query)
+		if err != nil {
+			return fmt.Errorf("failed to fetch logs for range %d-%d: %v", chunkStart, chunkEnd, err)
+		}
+
+		sort.Slice(logs, func(i, j int) bool {
+			if logs[i].BlockNumber != logs[j].BlockNumber {
+				return logs[i].BlockNumber < logs[j].BlockNumber
+			}
+			return logs[i].Index < logs[j].Index
+		})
+
+		for _, vLog := range logs {
+			if !ignoreCheckpoint {
+				last, has := cp.get(vLog.Address)
+				if !has {
+					// No checkpoint yet for this pool: it was added after fromBlock was computed
+					// from other pools' checkpoints, so replaying it here would be relative to an
+					// unrelated pool's progress. Leave it to the live subscription instead.
+					continue
+				}
+				if vLog.BlockNumber <= last {
+					continue // already notified on a previous run
+				}
+			}
+
+			pool, ok := poolsByAddress[vLog.Address]
+			if !ok {
+				continue
+			}
+
+			event, parseErr := parseAllocateStablecoinsEvent(contractABI, vLog, pool.TokenName, pool.YBURL)
+			if parseErr != nil {
+				log.Printf("❌ Failed to unpack backfilled AllocateStablecoins event: %v", parseErr)
+				continue
+			}
+
+			decimals := resolvePoolDecimals(ctx, client, pool)
+
+			if err := sendTelegramNotification(bot, chatID, event, vLog.TxHash.Hex(), true, decimals, pool.EtherscanBaseURL); err != nil {
+				return fmt.Errorf("failed to send backfill notification for block %d: %v", vLog.BlockNumber, err)
+			}
+
+			if err := cp.setAndSave(vLog.Address, vLog.BlockNumber, checkpointPath); err != nil {
+				return fmt.Errorf("failed to persist checkpoint: %v", err)
+			}
+		}
+	}
+
+	log.Println("✅ Backfill complete, handing off to live subscription")
+	return nil
+}
+
+// performStartupBackfill replays whatever AllocateStablecoins events were missed since the last
+// checkpoint (or since replayFrom, for a manual re-run) before the live subscription takes over,
+// one endpoint at a time since each endpoint is its own chain with its own block numbering.
+func performStartupBackfill(config *Config, cp *checkpoint, replayFrom *uint64) error {
+	contractABI, err := loadABI()
+	if err != nil {
+		return fmt.Errorf("failed to load ABI for backfill: %v", err)
+	}
+	allocateHash := allocateStablecoinsSignature()
+
+	bot, err := tgbotapi.NewBotAPI(config.TelegramToken)
+	if err != nil {
+		return fmt.Errorf("failed to create Telegram bot for backfill: %v", err)
+	}
+
+	for wsURL, pools := range groupPoolsByEndpoint(config.Pools) {
+		if err := backfillEndpoint(wsURL, pools, contractABI, bot, config, cp, allocateHash, replayFrom); err != nil {
+			return fmt.Errorf("backfill failed for endpoint %s: %v", wsURL, err)
+		}
+	}
+
+	return nil
+}
+
+func backfillEndpoint(wsURL string, pools []PoolInfo, contractABI abi.ABI, bot *tgbotapi.BotAPI, config *Config, cp *checkpoint, allocateHash common.Hash, replayFrom *uint64) error {
+	client, err := ethclient.Dial(wsURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Ethereum client for backfill: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch block number for backfill: %v", err)
+	}
+
+	fromBlock, toBlock, ok := backfillRange(pools, cp, config.MinConfirmations, head, replayFrom)
+	if !ok {
+		log.Printf("⏩ [%s] Nothing to backfill, starting live subscription", wsURL)
+		return nil
+	}
+
+	return runBackfill(ctx, client, contractABI, bot, config.TelegramChatID, cp, config.CheckpointPath, pools, allocateHash, fromBlock, toBlock, replayFrom != nil)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackfillRange(t *testing.T) {
+	addr1 := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	addr2 := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	pools := []PoolInfo{{Address: addr1}, {Address: addr2}}
+
+	t.Run("head below minConfirmations has nothing to replay", func(t *testing.T) {
+		cp := newCheckpoint()
+		_, _, ok := backfillRange(pools, cp, 10, 5, nil)
+		assert.False(t, ok)
+	})
+
+	t.Run("replayFrom overrides any checkpoint", func(t *testing.T) {
+		cp := newCheckpoint()
+		cp.data[checkpointKey(addr1)] = 40
+		replayFrom := uint64(50)
+
+		from, to, ok := backfillRange(pools, cp, 3, 100, &replayFrom)
+		assert.True(t, ok)
+		assert.Equal(t, uint64(50), from)
+		assert.Equal(t, uint64(97), to)
+	})
+
+	t.Run("no pool has a checkpoint yet", func(t *testing.T) {
+		cp := newCheckpoint()
+		_, _, ok := backfillRange(pools, cp, 3, 100, nil)
+		assert.False(t, ok, "pools with no checkpoint should be left to the live subscription, not backfilled")
+	})
+
+	t.Run("fromBlock is the earliest checkpoint across pools, plus one", func(t *testing.T) {
+		cp := newCheckpoint()
+		cp.data[checkpointKey(addr1)] = 60
+		cp.data[checkpointKey(addr2)] = 40
+
+		from, to, ok := backfillRange(pools, cp, 3, 100, nil)
+		assert.True(t, ok)
+		assert.Equal(t, uint64(41), from)
+		assert.Equal(t, uint64(97), to)
+	})
+
+	t.Run("fromBlock past toBlock has nothing to replay", func(t *testing.T) {
+		cp := newCheckpoint()
+		cp.data[checkpointKey(addr1)] = 99
+
+		_, _, ok := backfillRange(pools, cp, 3, 100, nil)
+		assert.False(t, ok)
+	})
+}
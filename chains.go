@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// poolRuntime bundles the state resolved once a pool's endpoint connects: the client to query it
+// with, the confirmation queue its events are buffered in, and its (possibly lazily-fetched)
+// token decimals.
+type poolRuntime struct {
+	info   PoolInfo
+	client *ethclient.Client
+	queue  *confirmationQueue
+
+	decMu    sync.Mutex
+	decimals int
+}
+
+func newPoolRuntime(info PoolInfo, client *ethclient.Client, queue *confirmationQueue) *poolRuntime {
+	return &poolRuntime{info: info, client: client, queue: queue, decimals: info.Decimals}
+}
+
+// resolveDecimals returns the pool's token decimals, resolving and caching them via
+// resolvePoolDecimals the first time they're needed if the pool didn't configure one. This never
+// fails: a pool with no usable token contract just gets fallbackDecimals.
+func (pr *poolRuntime) resolveDecimals(ctx context.Context) int {
+	pr.decMu.Lock()
+	defer pr.decMu.Unlock()
+
+	if pr.decimals != unknownDecimals {
+		return pr.decimals
+	}
+
+	pr.decimals = resolvePoolDecimals(ctx, pr.client, pr.info)
+	return pr.decimals
+}
+
+// poolRuntimeRegistry maps a pool's address to its resolved runtime state. It's populated by
+// each chain's subscription goroutine before that goroutine starts forwarding logs, and read by
+// event handlers dispatching on whichever goroutine's logs channel a log arrived on.
+type poolRuntimeRegistry struct {
+	mu     sync.RWMutex
+	byAddr map[common.Address]*poolRuntime
+}
+
+func newPoolRuntimeRegistry() *poolRuntimeRegistry {
+	return &poolRuntimeRegistry{byAddr: make(map[common.Address]*poolRuntime)}
+}
+
+func (r *poolRuntimeRegistry) set(addr common.Address, pr *poolRuntime) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.byAddr[addr] = pr
+}
+
+func (r *poolRuntimeRegistry) get(addr common.Address) (*poolRuntime, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	pr, ok := r.byAddr[addr]
+	return pr, ok
+}
+
+// runChainSubscription owns one RPC endpoint for the lifetime of the process: it dials, builds
+// runtime state for every pool on that endpoint, subscribes, and forwards logs onto the shared
+// logsOut channel. On any error it redials and resubscribes after a short delay, independently of
+// every other endpoint's goroutine, until ctx is cancelled (e.g. on SIGTERM).
+func runChainSubscription(ctx context.Context, wsURL string, pools []PoolInfo, queue *confirmationQueue, poolRegistry *poolRuntimeRegistry, logsOut chan<- types.Log, minConfirmations uint64) {
+	for ctx.Err() == nil {
+		if err := connectAndSubscribe(ctx, wsURL, pools, queue, poolRegistry, logsOut, minConfirmations); err != nil {
+			log.Printf("❌ [%s] chain subscription stopped: %v", wsURL, err)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		wsReconnectsTotal.Inc()
+		log.Printf("🔄 [%s] retrying in 10 seconds...", wsURL)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(10 * time.Second):
+		}
+	}
+}
+
+func connectAndSubscribe(ctx context.Context, wsURL string, pools []PoolInfo, queue *confirmationQueue, poolRegistry *poolRuntimeRegistry, logsOut chan<- types.Log, minConfirmations uint64) error {
+	client, err := ethclient.Dial(wsURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	contractAddresses := make([]common.Address, 0, len(pools))
+	for _, pool := range pools {
+		contractAddresses = append(contractAddresses, pool.Address)
+		poolRegistry.set(pool.Address, newPoolRuntime(pool, client, queue))
+		log.Printf("🔍 Monitoring %s pool (chain %d): %s", pool.TokenName, pool.ChainID, pool.Address.Hex())
+	}
+
+	// No Topics filter: we subscribe to every log the watched contracts emit, not just the ones a
+	// handler is registered for, so a genuinely unrecognized event still reaches the dispatch loop
+	// and falls through to the generic unparsed-event notification instead of being filtered out
+	// at the node before it ever arrives.
+	query := ethereum.FilterQuery{
+		Addresses: contractAddresses,
+	}
+
+	logs := make(chan types.Log)
+	sub, err := client.SubscribeFilterLogs(ctx, query, logs)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to logs: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	// Re-checks whatever is still buffered (e.g. from before this endpoint's WebSocket
+	// reconnect) and notifies once events reach MinConfirmations.
+	confirmCtx, cancelConfirm := context.WithCancel(ctx)
+	defer cancelConfirm()
+	go runConfirmationLoop(confirmCtx, client, queue, minConfirmations)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-sub.Err():
+			return fmt.Errorf("subscription error: %v", err)
+		case vLog := <-logs:
+			logsOut <- vLog
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// defaultCheckpointPath is where the last-notified block per pool is persisted when
+// CHECKPOINT_PATH is not set.
+const defaultCheckpointPath = "./checkpoint.json"
+
+// checkpoint tracks, per pool address (lowercase hex), the block number of the last event that
+// was successfully notified on. It is consulted on startup to backfill whatever was missed while
+// the bot was down, and to avoid re-notifying events already sent. With one confirmation loop per
+// RPC endpoint (chunk0-4), several endpoints' notify closures can reach it at the same time, so
+// every access is guarded by mu.
+type checkpoint struct {
+	mu   sync.Mutex
+	data map[string]uint64
+}
+
+func newCheckpoint() *checkpoint {
+	return &checkpoint{data: make(map[string]uint64)}
+}
+
+// checkpointKey normalizes a pool address into the map key used by checkpoint.
+func checkpointKey(addr common.Address) string {
+	return strings.ToLower(addr.Hex())
+}
+
+// get returns the last notified block for addr, if any.
+func (cp *checkpoint) get(addr common.Address) (uint64, bool) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	last, ok := cp.data[checkpointKey(addr)]
+	return last, ok
+}
+
+// setAndSave records addr's last notified block and persists the checkpoint, all under the same
+// lock so a concurrent writer can never see (or save) a half-updated map. The update is
+// monotonic: confirmations within the same poll tick can land out of block order (runConfirmationLoop
+// iterates a map snapshot), so a lower block number arriving after a higher one is a no-op rather
+// than a regression that would make a future restart replay and re-notify on the already-sent one.
+func (cp *checkpoint) setAndSave(addr common.Address, block uint64, path string) error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	key := checkpointKey(addr)
+	if block <= cp.data[key] {
+		return nil
+	}
+
+	cp.data[key] = block
+	return cp.saveLocked(path)
+}
+
+// loadCheckpoint reads the checkpoint file at path. A missing file is not an error: it just means
+// there is nothing to backfill from yet.
+func loadCheckpoint(path string) (*checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return newCheckpoint(), nil
+		}
+		return nil, fmt.Errorf("failed to read checkpoint file: %v", err)
+	}
+
+	cp := newCheckpoint()
+	if err := json.Unmarshal(data, &cp.data); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint file: %v", err)
+	}
+	return cp, nil
+}
+
+// saveLocked persists the checkpoint atomically: it writes to a temp file in the same directory
+// and renames it over path, so a crash mid-write never leaves a corrupt or partial checkpoint.
+// Callers must hold cp.mu.
+func (cp *checkpoint) saveLocked(path string) error {
+	data, err := json.Marshal(cp.data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".checkpoint-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp checkpoint file: %v", err)
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write temp checkpoint file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to close temp checkpoint file: %v", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to rename temp checkpoint file into place: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// confirmationPollInterval controls how often buffered events are re-checked against the chain head.
+const confirmationPollInterval = 15 * time.Second
+
+// pendingKey uniquely identifies a log so it can never be confirmed or dropped twice.
+type pendingKey struct {
+	BlockHash common.Hash
+	TxHash    common.Hash
+	LogIndex  uint
+}
+
+// pendingEvent is a log observed on the live subscription that is waiting for MinConfirmations
+// before it is safe to notify on.
+type pendingEvent struct {
+	key         pendingKey
+	address     common.Address
+	blockNumber uint64
+	notify      func() error
+}
+
+// confirmationQueue buffers events seen on the subscription until they are deep enough to be
+// considered final, protecting users from notifications about transactions a reorg later drops.
+// monitorEvents creates one per RPC endpoint the first time it sees that endpoint and keeps it in
+// main's queues map, so it is shared across reconnects and monitorEvents retries and a WebSocket
+// reconnect re-checks whatever was still buffered instead of losing it.
+type confirmationQueue struct {
+	mu      sync.Mutex
+	pending map[pendingKey]pendingEvent
+}
+
+func newConfirmationQueue() *confirmationQueue {
+	return &confirmationQueue{pending: make(map[pendingKey]pendingEvent)}
+}
+
+func (q *confirmationQueue) add(ev pendingEvent) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending[ev.key] = ev
+	pendingConfirmations.WithLabelValues(ev.address.Hex()).Set(float64(q.countForAddress(ev.address)))
+}
+
+func (q *confirmationQueue) remove(key pendingKey) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	address := q.pending[key].address
+	delete(q.pending, key)
+	pendingConfirmations.WithLabelValues(address.Hex()).Set(float64(q.countForAddress(address)))
+}
+
+// countForAddress returns how many events for address are currently buffered. Callers must hold
+// q.mu.
+func (q *confirmationQueue) countForAddress(address common.Address) int {
+	count := 0
+	for _, ev := range q.pending {
+		if ev.address == address {
+			count++
+		}
+	}
+	return count
+}
+
+func (q *confirmationQueue) snapshot() []pendingEvent {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	events := make([]pendingEvent, 0, len(q.pending))
+	for _, ev := range q.pending {
+		events = append(events, ev)
+	}
+	return events
+}
+
+// runConfirmationLoop periodically checks buffered events against the chain head. Once an event
+// reaches MinConfirmations it re-queries the log's block hash to make sure it is still canonical
+// before notifying; if the block hash was reorged out, the event is dropped silently. An event is
+// only removed from the queue once it is reorged-out or successfully notified on — a notify
+// failure (e.g. a transient Telegram error) leaves it buffered so the next tick retries instead of
+// losing it.
+func runConfirmationLoop(ctx context.Context, client *ethclient.Client, queue *confirmationQueue, minConfirmations uint64) {
+	ticker := time.NewTicker(confirmationPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			head, err := client.BlockNumber(ctx)
+			if err != nil {
+				log.Printf("⚠️ Failed to fetch block number for confirmation check: %v", err)
+				continue
+			}
+
+			for _, ev := range queue.snapshot() {
+				if head < ev.blockNumber || head-ev.blockNumber < minConfirmations {
+					continue
+				}
+
+				confirmed, err := isLogStillCanonical(ctx, client, ev.key, ev.address)
+				if err != nil {
+					log.Printf("⚠️ Failed to re-check log %s for canonicality: %v", ev.key.TxHash.Hex(), err)
+					continue
+				}
+
+				if !confirmed {
+					queue.remove(ev.key)
+					log.Printf("⛔ Dropping reorged-out event TxHash: %s", ev.key.TxHash.Hex())
+					continue
+				}
+
+				if err := ev.notify(); err != nil {
+					log.Printf("❌ Failed to send notification for confirmed event, will retry next tick: %v", err)
+					continue
+				}
+
+				queue.remove(ev.key)
+			}
+		}
+	}
+}
+
+// isLogStillCanonical re-queries logs restricted to the block hash the event was originally seen
+// on. It first confirms the block hash itself still resolves: ethereum.NotFound there means the
+// block was reorged out of the canonical chain, which is the only case that should drop the
+// event. Any other error (a dropped connection, a node timing out, ...) is transient and is
+// returned to the caller so the event stays buffered for the next poll instead of being dropped.
+func isLogStillCanonical(ctx context.Context, client *ethclient.Client, key pendingKey, address common.Address) (bool, error) {
+	if _, err := client.HeaderByHash(ctx, key.BlockHash); err != nil {
+		if errors.Is(err, ethereum.NotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to look up block %s: %v", key.BlockHash.Hex(), err)
+	}
+
+	query := ethereum.FilterQuery{
+		BlockHash: &key.BlockHash,
+		Addresses: []common.Address{address},
+	}
+
+	logs, err := client.FilterLogs(ctx, query)
+	if err != nil {
+		return false, fmt.Errorf("failed to filter logs for block %s: %v", key.BlockHash.Hex(), err)
+	}
+
+	for _, l := range logs {
+		if l.TxHash == key.TxHash && l.Index == key.LogIndex {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
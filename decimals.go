@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// fallbackDecimals is used whenever a pool's decimals aren't configured and there is no token
+// contract to query (or that query fails), matching the stablecoins this bot has historically
+// monitored.
+const fallbackDecimals = 18
+
+// resolvePoolDecimals returns pool's configured decimals, or - for a pool that left Decimals
+// unset - queries decimals() on its configured token contract. The stablecoin amounts
+// AllocateStablecoins reports are denominated in the token's own decimals, not the pool (LP)
+// contract's, so TokenAddress (not Address) is what gets queried. If no TokenAddress is
+// configured, or the call fails (e.g. the contract doesn't implement decimals()), this falls back
+// to fallbackDecimals rather than failing the notification.
+func resolvePoolDecimals(ctx context.Context, client *ethclient.Client, pool PoolInfo) int {
+	if pool.Decimals != unknownDecimals {
+		return pool.Decimals
+	}
+	if pool.TokenAddress == (common.Address{}) {
+		return fallbackDecimals
+	}
+
+	decimals, err := fetchTokenDecimals(ctx, client, pool.TokenAddress)
+	if err != nil {
+		log.Printf("⚠️ Failed to fetch decimals for token %s, falling back to %d: %v", pool.TokenAddress.Hex(), fallbackDecimals, err)
+		return fallbackDecimals
+	}
+	return decimals
+}
+
+// decimalsDivisor returns 10^decimals as a big.Float, used to scale a raw token amount down to a
+// human-readable one.
+func decimalsDivisor(decimals int) *big.Float {
+	return new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil))
+}
+
+// erc20DecimalsABI is the minimal ABI fragment needed to call the standard ERC20 decimals() view
+// function on a pool's token contract.
+const erc20DecimalsABI = `[{"constant":true,"inputs":[],"name":"decimals","outputs":[{"name":"","type":"uint8"}],"stateMutability":"view","type":"function"}]`
+
+var decimalsABI = mustParseDecimalsABI()
+
+func mustParseDecimalsABI() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(erc20DecimalsABI))
+	if err != nil {
+		panic(fmt.Sprintf("failed to parse embedded ERC20 decimals ABI: %v", err))
+	}
+	return parsed
+}
+
+// fetchTokenDecimals calls the standard ERC20 decimals() view function on address via client.
+func fetchTokenDecimals(ctx context.Context, client *ethclient.Client, address common.Address) (int, error) {
+	data, err := decimalsABI.Pack("decimals")
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode decimals() call: %v", err)
+	}
+
+	result, err := client.CallContract(ctx, ethereum.CallMsg{To: &address, Data: data}, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to call decimals() on %s: %v", address.Hex(), err)
+	}
+
+	var decimals uint8
+	if err := decimalsABI.UnpackIntoInterface(&decimals, "decimals", result); err != nil {
+		return 0, fmt.Errorf("failed to decode decimals() result: %v", err)
+	}
+
+	return int(decimals), nil
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// EventHandler decodes and notifies on a single contract event type. Handlers are registered in
+// an eventRegistry keyed by topic signature so monitorEvents can dispatch incoming logs without
+// hardcoding event hashes. Subscriptions are not filtered by these signatures: a log whose topic
+// matches no registered handler still reaches the dispatch loop and falls through to the generic
+// unparsed-event notification, rather than being filtered out before it ever arrives.
+type EventHandler interface {
+	// Signature is the keccak256 hash of the event's canonical signature, used as the log topic.
+	Signature() common.Hash
+	// Name is the human-readable event name used in logs, Telegram messages, and POOL_EVENTS.
+	Name() string
+	// Handle decodes vLog and sends (or buffers) the resulting notification.
+	Handle(ctx context.Context, vLog types.Log, bot *tgbotapi.BotAPI, chatID string) error
+}
+
+// eventRegistry maps event topic signatures to the handler responsible for them.
+type eventRegistry struct {
+	byHash map[common.Hash]EventHandler
+}
+
+func newEventRegistry() *eventRegistry {
+	return &eventRegistry{byHash: make(map[common.Hash]EventHandler)}
+}
+
+func (r *eventRegistry) register(h EventHandler) {
+	r.byHash[h.Signature()] = h
+}
+
+func (r *eventRegistry) lookup(topic common.Hash) (EventHandler, bool) {
+	h, ok := r.byHash[topic]
+	return h, ok
+}
+
+// allocateStablecoinsHandler decodes AllocateStablecoins logs and buffers them behind the
+// confirmation queue so a reorg can't leave users notified about a transaction that no longer
+// exists on the canonical chain. Per-pool state (token name, YB URL, decimals, explorer base URL,
+// and which confirmation queue to buffer into) is resolved per log from the shared pool registry,
+// since a single handler now serves pools spread across multiple chains/endpoints.
+type allocateStablecoinsHandler struct {
+	contractABI    abi.ABI
+	pools          *poolRuntimeRegistry
+	cp             *checkpoint
+	checkpointPath string
+}
+
+func newAllocateStablecoinsHandler(contractABI abi.ABI, pools *poolRuntimeRegistry, cp *checkpoint, checkpointPath string) *allocateStablecoinsHandler {
+	return &allocateStablecoinsHandler{
+		contractABI:    contractABI,
+		pools:          pools,
+		cp:             cp,
+		checkpointPath: checkpointPath,
+	}
+}
+
+// allocateStablecoinsSignature is a package-level function so the startup backfill can build the
+// same filter topic without constructing a full handler.
+func allocateStablecoinsSignature() common.Hash {
+	return crypto.Keccak256Hash([]byte("AllocateStablecoins(address,uint256,uint256)"))
+}
+
+func (h *allocateStablecoinsHandler) Signature() common.Hash {
+	return allocateStablecoinsSignature()
+}
+
+func (h *allocateStablecoinsHandler) Name() string {
+	return "AllocateStablecoins"
+}
+
+func (h *allocateStablecoinsHandler) Handle(ctx context.Context, vLog types.Log, bot *tgbotapi.BotAPI, chatID string) error {
+	receivedAt := time.Now()
+
+	pr, ok := h.pools.get(vLog.Address)
+	if !ok {
+		notifyErrorsTotal.WithLabelValues("unknown_pool").Inc()
+		return fmt.Errorf("no pool runtime registered for %s", vLog.Address.Hex())
+	}
+
+	event, err := parseAllocateStablecoinsEvent(h.contractABI, vLog, pr.info.TokenName, pr.info.YBURL)
+	if err != nil {
+		log.Printf("❌ Failed to unpack AllocateStablecoins event: %v", err)
+		notifyErrorsTotal.WithLabelValues("parse_failed").Inc()
+		return sendUnparsedEventNotification(bot, chatID, h.Name(), pr.info.TokenName, vLog.Address, vLog.TxHash.Hex(), vLog.Data, pr.info.EtherscanBaseURL)
+	}
+
+	decimals := pr.resolveDecimals(ctx)
+
+	// Buffer until MinConfirmations pass instead of notifying immediately, so a reorg dropping
+	// this log doesn't leave users with a message about a transaction that no longer exists on
+	// the canonical chain.
+	logCopy := vLog
+	pr.queue.add(pendingEvent{
+		key: pendingKey{
+			BlockHash: logCopy.BlockHash,
+			TxHash:    logCopy.TxHash,
+			LogIndex:  logCopy.Index,
+		},
+		address:     logCopy.Address,
+		blockNumber: logCopy.BlockNumber,
+		notify: func() error {
+			if err := sendTelegramNotification(bot, chatID, event, logCopy.TxHash.Hex(), false, decimals, pr.info.EtherscanBaseURL); err != nil {
+				return err
+			}
+			notifyLatencySeconds.Observe(time.Since(receivedAt).Seconds())
+			return h.cp.setAndSave(logCopy.Address, logCopy.BlockNumber, h.checkpointPath)
+		},
+	})
+	log.Printf("⏳ Buffered AllocateStablecoins event for confirmation, TxHash: %s", logCopy.TxHash.Hex())
+	return nil
+}
+
+// approvalHandler decodes Approval logs and notifies immediately: unlike AllocateStablecoins, an
+// approval carries no deposit-capacity claim that a reorg could later invalidate for users.
+type approvalHandler struct {
+	contractABI abi.ABI
+	pools       *poolRuntimeRegistry
+}
+
+func newApprovalHandler(contractABI abi.ABI, pools *poolRuntimeRegistry) *approvalHandler {
+	return &approvalHandler{contractABI: contractABI, pools: pools}
+}
+
+func (h *approvalHandler) Signature() common.Hash {
+	return crypto.Keccak256Hash([]byte("Approval(address,address,uint256)"))
+}
+
+func (h *approvalHandler) Name() string {
+	return "Approval"
+}
+
+func (h *approvalHandler) Handle(ctx context.Context, vLog types.Log, bot *tgbotapi.BotAPI, chatID string) error {
+	receivedAt := time.Now()
+
+	pr, ok := h.pools.get(vLog.Address)
+	if !ok {
+		notifyErrorsTotal.WithLabelValues("unknown_pool").Inc()
+		return fmt.Errorf("no pool runtime registered for %s", vLog.Address.Hex())
+	}
+
+	var event ApprovalEvent
+	if err := h.contractABI.UnpackIntoInterface(&event, "Approval", vLog.Data); err != nil {
+		log.Printf("❌ Failed to unpack Approval event: %v", err)
+		notifyErrorsTotal.WithLabelValues("parse_failed").Inc()
+		return sendUnparsedEventNotification(bot, chatID, h.Name(), pr.info.TokenName, vLog.Address, vLog.TxHash.Hex(), vLog.Data, pr.info.EtherscanBaseURL)
+	}
+
+	// Owner and spender are indexed, so they arrive as topics rather than in the data payload.
+	if len(vLog.Topics) > 2 {
+		event.Owner = common.HexToAddress(vLog.Topics[1].Hex())
+		event.Spender = common.HexToAddress(vLog.Topics[2].Hex())
+	}
+	event.PoolAddress = vLog.Address
+	event.TokenName = pr.info.TokenName
+
+	decimals := pr.resolveDecimals(ctx)
+
+	if err := sendApprovalNotification(bot, chatID, &event, vLog.TxHash.Hex(), decimals, pr.info.EtherscanBaseURL); err != nil {
+		return err
+	}
+	notifyLatencySeconds.Observe(time.Since(receivedAt).Seconds())
+	return nil
+}
+
+// sendApprovalNotification formats an Approval event for Telegram, scaling the raw value by the
+// pool's token decimals and linking to the pool's own chain explorer.
+func sendApprovalNotification(bot *tgbotapi.BotAPI, chatID string, event *ApprovalEvent, txHash string, decimals int, etherscanBaseURL string) error {
+	value := new(big.Float).Quo(new(big.Float).SetInt(event.Value), decimalsDivisor(decimals))
+
+	message := fmt.Sprintf(`🔏 *YieldBasis %s Pool Approval*
+
+*Pool*: %s Pool
+*Event*: Approval
+
+*Owner*: %s
+*Spender*: %s
+*Value*: %.2f
+
+*Transaction*: [View on Explorer](%s/tx/%s)`,
+		event.TokenName,
+		event.TokenName,
+		event.Owner.Hex(),
+		event.Spender.Hex(),
+		value,
+		etherscanBaseURL,
+		txHash)
+
+	msg := tgbotapi.NewMessageToChannel("@"+chatID, message)
+	msg.ParseMode = "Markdown"
+	msg.DisableWebPagePreview = true
+
+	// If chatID is not a channel, treat it as a regular chat ID
+	if !strings.HasPrefix(chatID, "@") {
+		if chatIDInt, err := strconv.ParseInt(chatID, 10, 64); err == nil {
+			msg.ChatID = chatIDInt
+		}
+	}
+
+	_, err := bot.Send(msg)
+	if err != nil {
+		eventsNotifiedTotal.WithLabelValues(event.TokenName, "Approval", "error").Inc()
+		notifyErrorsTotal.WithLabelValues("telegram_send_failed").Inc()
+	} else {
+		eventsNotifiedTotal.WithLabelValues(event.TokenName, "Approval", "success").Inc()
+	}
+	return err
+}
@@ -2,24 +2,28 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"math/big"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
-	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/joho/godotenv"
 )
 
+// defaultMinConfirmations is how many blocks must build on top of a log's block before a
+// buffered event is considered final and safe to notify on.
+const defaultMinConfirmations = 3
+
 type AllocateStablecoinsEvent struct {
 	Allocator            common.Address
 	StablecoinAllocation *big.Int
@@ -37,17 +41,15 @@ type ApprovalEvent struct {
 	TokenName   string         // Added to identify token name
 }
 
-type PoolInfo struct {
-	Address   common.Address
-	TokenName string
-	YBURL     string
-}
-
 type Config struct {
-	InfuraWsURL    string
-	TelegramToken  string
-	TelegramChatID string
-	Pools          []PoolInfo
+	InfuraWsURL      string
+	TelegramToken    string
+	TelegramChatID   string
+	Pools            []PoolInfo
+	MinConfirmations uint64
+	CheckpointPath   string
+	PoolEvents       poolEventsConfig
+	MetricsAddr      string
 }
 
 func loadConfig() (*Config, error) {
@@ -73,54 +75,38 @@ func loadConfig() (*Config, error) {
 		return nil, fmt.Errorf("TELEGRAM_CHAT_ID is required")
 	}
 
-	// Parse pool addresses
-	poolAddresses := os.Getenv("POOL_ADDRESSES")
-	if poolAddresses == "" {
-		// Default pools if not specified
-		poolAddresses = "0x6095a220C5567360d459462A25b1AD5aEAD45204,0x2B513eBe7070Cff91cf699a0BFe5075020C732FF,0xD6a1147666f6E4d7161caf436d9923D44d901112"
+	// Resolve pools either from POOLS_CONFIG_PATH (multi-chain) or from the legacy
+	// POOL_ADDRESSES + per-pool env vars, defaulting every pool to InfuraWsURL.
+	pools, err := loadPools(config.InfuraWsURL)
+	if err != nil {
+		return nil, err
 	}
-
-	// Define pool to token mapping (all addresses in lowercase for consistent lookup)
-	poolTokenMap := map[string]string{
-		"0x6095a220c5567360d459462a25b1ad5aead45204": "WBTC",
-		"0x2b513ebe7070cff91cf699a0bfe5075020c732ff": "TBTC",
-		"0xd6a1147666f6e4d7161caf436d9923d44d901112": "CBBTC",
+	config.Pools = pools
+
+	// Parse confirmation depth required before a buffered event is notified on.
+	config.MinConfirmations = defaultMinConfirmations
+	if minConfEnv := os.Getenv("MIN_CONFIRMATIONS"); minConfEnv != "" {
+		minConf, err := strconv.ParseUint(minConfEnv, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MIN_CONFIRMATIONS: %v", err)
+		}
+		config.MinConfirmations = minConf
 	}
 
-	// Define YieldBasis interface URLs for each pool
-	poolYBURLMap := map[string]string{
-		"0xa5bfb61af14afe7b81cac7fa4f7c4483dedc36df": "https://yieldbasis.com/market/0x6095a220C5567360d459462A25b1AD5aEAD45204",
-		"0x2b513ebe7070cff91cf699a0bfe5075020c732ff": "https://yieldbasis.com/market/0x2B513eBe7070Cff91cf699a0BFe5075020C732FF",
-		"0xd6a1147666f6e4d7161caf436d9923d44d901112": "https://yieldbasis.com/market/0xD6a1147666f6E4d7161caf436d9923D44d901112",
+	config.CheckpointPath = os.Getenv("CHECKPOINT_PATH")
+	if config.CheckpointPath == "" {
+		config.CheckpointPath = defaultCheckpointPath
 	}
 
-	// Parse addresses and create pool info
-	addressList := strings.Split(poolAddresses, ",")
-	for _, addr := range addressList {
-		addr = strings.TrimSpace(addr)
-		if !common.IsHexAddress(addr) {
-			return nil, fmt.Errorf("invalid pool address: %s", addr)
-		}
-
-		tokenName := poolTokenMap[strings.ToLower(addr)]
-		if tokenName == "" {
-			tokenName = "UNKNOWN"
-		}
-
-		ybURL := poolYBURLMap[strings.ToLower(addr)]
-		if ybURL == "" {
-			ybURL = "https://yieldbasis.com"
-		}
-
-		config.Pools = append(config.Pools, PoolInfo{
-			Address:   common.HexToAddress(addr),
-			TokenName: tokenName,
-			YBURL:     ybURL,
-		})
+	poolEvents, err := parsePoolEvents(os.Getenv("POOL_EVENTS"))
+	if err != nil {
+		return nil, err
 	}
+	config.PoolEvents = poolEvents
 
-	if len(config.Pools) == 0 {
-		return nil, fmt.Errorf("no valid pool addresses found")
+	config.MetricsAddr = os.Getenv("METRICS_ADDR")
+	if config.MetricsAddr == "" {
+		config.MetricsAddr = defaultMetricsAddr
 	}
 
 	return config, nil
@@ -140,13 +126,14 @@ func loadABI() (abi.ABI, error) {
 	return contractABI, nil
 }
 
-func sendTelegramNotification(bot *tgbotapi.BotAPI, chatID string, event *AllocateStablecoinsEvent, txHash string) error {
-	// Format amounts in a readable way (assuming 18 decimals for stablecoin)
-	allocation := new(big.Float).Quo(new(big.Float).SetInt(event.StablecoinAllocation), big.NewFloat(1e18))
-	allocated := new(big.Float).Quo(new(big.Float).SetInt(event.StablecoinAllocated), big.NewFloat(1e18))
+func sendTelegramNotification(bot *tgbotapi.BotAPI, chatID string, event *AllocateStablecoinsEvent, txHash string, isBackfill bool, decimals int, etherscanBaseURL string) error {
+	// Format amounts in a readable way, scaled by the pool's own token decimals
+	divisor := decimalsDivisor(decimals)
+	allocation := new(big.Float).Quo(new(big.Float).SetInt(event.StablecoinAllocation), divisor)
+	allocated := new(big.Float).Quo(new(big.Float).SetInt(event.StablecoinAllocated), divisor)
 
 	difference := new(big.Int).Sub(event.StablecoinAllocated, event.StablecoinAllocation)
-	diffFloat := new(big.Float).Quo(new(big.Float).SetInt(difference.Abs(difference)), big.NewFloat(1e18))
+	diffFloat := new(big.Float).Quo(new(big.Float).SetInt(difference.Abs(difference)), divisor)
 
 	var changeText string
 	if difference.Sign() > 0 {
@@ -157,8 +144,13 @@ func sendTelegramNotification(bot *tgbotapi.BotAPI, chatID string, event *Alloca
 		changeText = "No change"
 	}
 
-	message := fmt.Sprintf(`🚀 *YieldBasis %s Pool Cap Update*
-	
+	titleSuffix := ""
+	if isBackfill {
+		titleSuffix = " _[backfill]_"
+	}
+
+	message := fmt.Sprintf(`🚀 *YieldBasis %s Pool Cap Update*%s
+
 *YieldBasis Interface*: [View %s Pool](%s)
 
 *Pool*: %s Pool
@@ -168,14 +160,14 @@ func sendTelegramNotification(bot *tgbotapi.BotAPI, chatID string, event *Alloca
 *Allocated*: %.2f stablecoins
 *Change*: %s
 
-*Transaction*: [View on Etherscan](https://etherscan.io/tx/%s)
+*Transaction*: [View on Etherscan](%s/tx/%s)
 
 *New %s deposit capacity available!*`,
-		event.TokenName,
+		event.TokenName, titleSuffix,
 		event.TokenName, event.YBURL,
 		event.TokenName,
 		allocation, allocated, changeText,
-		txHash,
+		etherscanBaseURL, txHash,
 		event.TokenName)
 
 	msg := tgbotapi.NewMessageToChannel("@"+chatID, message)
@@ -191,10 +183,20 @@ func sendTelegramNotification(bot *tgbotapi.BotAPI, chatID string, event *Alloca
 	}
 
 	_, err := bot.Send(msg)
+	if err != nil {
+		eventsNotifiedTotal.WithLabelValues(event.TokenName, "AllocateStablecoins", "error").Inc()
+		notifyErrorsTotal.WithLabelValues("telegram_send_failed").Inc()
+	} else {
+		eventsNotifiedTotal.WithLabelValues(event.TokenName, "AllocateStablecoins", "success").Inc()
+	}
 	return err
 }
 
-func sendUnparsedEventNotification(bot *tgbotapi.BotAPI, chatID string, eventType string, tokenName string, poolAddress common.Address, txHash string, rawData []byte) error {
+func sendUnparsedEventNotification(bot *tgbotapi.BotAPI, chatID string, eventType string, tokenName string, poolAddress common.Address, txHash string, rawData []byte, etherscanBaseURL string) error {
+	if etherscanBaseURL == "" {
+		etherscanBaseURL = defaultEtherscanBaseURL
+	}
+
 	message := fmt.Sprintf(`🚀 *YieldBasis %s Pool Event Detected*
 
 *Pool*: %s Pool
@@ -203,13 +205,13 @@ func sendUnparsedEventNotification(bot *tgbotapi.BotAPI, chatID string, eventTyp
 
 *Raw Event Data*: %s
 
-*Transaction*: [View on Etherscan](https://etherscan.io/tx/%s)
+*Transaction*: [View on Etherscan](%s/tx/%s)
 
 *Event detected but could not be parsed - please check transaction for details*`,
 		tokenName,
 		tokenName, eventType, poolAddress.Hex()[:10]+"...",
 		fmt.Sprintf("0x%x", rawData),
-		txHash)
+		etherscanBaseURL, txHash)
 
 	msg := tgbotapi.NewMessageToChannel("@"+chatID, message)
 	msg.ParseMode = "Markdown"
@@ -224,131 +226,148 @@ func sendUnparsedEventNotification(bot *tgbotapi.BotAPI, chatID string, eventTyp
 	}
 
 	_, err := bot.Send(msg)
+	if err != nil {
+		eventsNotifiedTotal.WithLabelValues(tokenName, eventType, "error").Inc()
+		notifyErrorsTotal.WithLabelValues("telegram_send_failed").Inc()
+	} else {
+		eventsNotifiedTotal.WithLabelValues(tokenName, eventType, "success").Inc()
+	}
 	return err
 }
 
-func monitorEvents(config *Config) error {
-	// Connect to Ethereum via WebSocket
-	client, err := ethclient.Dial(config.InfuraWsURL)
-	if err != nil {
-		return fmt.Errorf("failed to connect to Ethereum client: %v", err)
+// parseAllocateStablecoinsEvent decodes a raw log into an AllocateStablecoinsEvent, filling in
+// the indexed allocator address and the pool identification fields. Shared by the live
+// subscription path and the startup backfill so both notify on identically-shaped events.
+func parseAllocateStablecoinsEvent(contractABI abi.ABI, vLog types.Log, tokenName, ybURL string) (*AllocateStablecoinsEvent, error) {
+	var event AllocateStablecoinsEvent
+	if err := contractABI.UnpackIntoInterface(&event, "AllocateStablecoins", vLog.Data); err != nil {
+		return nil, err
 	}
-	defer client.Close()
 
-	// Load contract ABI
+	if len(vLog.Topics) > 1 {
+		event.Allocator = common.HexToAddress(vLog.Topics[1].Hex())
+	}
+
+	event.PoolAddress = vLog.Address
+	event.TokenName = tokenName
+	event.YBURL = ybURL
+
+	return &event, nil
+}
+
+// monitorEvents fans out one subscription goroutine per distinct RPC endpoint (so an Arbitrum
+// pool's connection and an Ethereum mainnet pool's connection fail and reconnect independently)
+// and fans their logs back into a single dispatch loop shared across chains. Subscriptions are
+// filtered by contract address only, not by topic, so any event a watched pool emits reaches the
+// dispatch loop: one with a registered, enabled handler is parsed and notified on, one with a
+// registered handler that POOL_EVENTS disables for its pool is dropped, and one with no registered
+// handler falls through to the generic unparsed-event notification.
+func monitorEvents(ctx context.Context, config *Config, queues map[string]*confirmationQueue, cp *checkpoint) error {
 	contractABI, err := loadABI()
 	if err != nil {
 		return fmt.Errorf("failed to load ABI: %v", err)
 	}
 
-	// Initialize Telegram bot
 	bot, err := tgbotapi.NewBotAPI(config.TelegramToken)
 	if err != nil {
 		return fmt.Errorf("failed to create Telegram bot: %v", err)
 	}
 	bot.Debug = false
-
 	log.Printf("Telegram bot initialized: %s", bot.Self.UserName)
 
-	// Create address to token and URL mapping for quick lookup
-	addressToToken := make(map[common.Address]string)
-	addressToYBURL := make(map[common.Address]string)
-	var contractAddresses []common.Address
-
-	for _, pool := range config.Pools {
-		contractAddresses = append(contractAddresses, pool.Address)
-		addressToToken[pool.Address] = pool.TokenName
-		addressToYBURL[pool.Address] = pool.YBURL
-		log.Printf("🔍 Monitoring %s pool: %s", pool.TokenName, pool.Address.Hex())
-	}
+	poolRegistry := newPoolRuntimeRegistry()
 
-	log.Println("This version of Cap-Monitor monitors only AllocateStablecoins event")
+	registry := newEventRegistry()
+	registry.register(newAllocateStablecoinsHandler(contractABI, poolRegistry, cp, config.CheckpointPath))
+	registry.register(newApprovalHandler(contractABI, poolRegistry))
 
-	// Get the event signature hashes
-	allocateSignature := []byte("AllocateStablecoins(address,uint256,uint256)")
-	allocateHash := crypto.Keccak256Hash(allocateSignature)
-
-	// Create filter for both AllocateStablecoins and Approval events on all pools
-	query := ethereum.FilterQuery{
-		Addresses: contractAddresses,
-		Topics:    [][]common.Hash{{allocateHash}},
-	}
-
-	// Subscribe to logs
+	groups := groupPoolsByEndpoint(config.Pools)
 	logs := make(chan types.Log)
-	sub, err := client.SubscribeFilterLogs(context.Background(), query, logs)
-	if err != nil {
-		return fmt.Errorf("failed to subscribe to logs: %v", err)
+
+	for wsURL, groupPools := range groups {
+		queue := queues[wsURL]
+		if queue == nil {
+			queue = newConfirmationQueue()
+			queues[wsURL] = queue
+		}
+		go runChainSubscription(ctx, wsURL, groupPools, queue, poolRegistry, logs, config.MinConfirmations)
 	}
-	defer sub.Unsubscribe()
 
 	log.Printf("📱 Telegram notifications will be sent to: %s", config.TelegramChatID)
-	log.Printf("🎯 Monitoring %d pools for AllocateStablecoins and Approval events...", len(config.Pools))
+	log.Printf("🎯 Monitoring %d pools across %d endpoint(s) for AllocateStablecoins and Approval events...", len(config.Pools), len(groups))
+	log.Printf("⏳ Buffering events for %d confirmations before notifying (reorg protection)", config.MinConfirmations)
 
-	// Monitor for events
+	// Monitor for events across every endpoint until the context is cancelled (e.g. SIGTERM).
 	for {
 		select {
-		case err := <-sub.Err():
-			return fmt.Errorf("subscription error: %v", err)
+		case <-ctx.Done():
+			return nil
 		case vLog := <-logs:
-			// Identify which pool this event came from
-			tokenName := addressToToken[vLog.Address]
-			ybURL := addressToYBURL[vLog.Address]
-			eventType := ""
-
-			// Determine event type by topic hash
-			if len(vLog.Topics) > 0 {
-				if vLog.Topics[0] == allocateHash {
-					eventType = "AllocateStablecoins"
-				}
+			tokenName := "UNKNOWN"
+			etherscanBaseURL := defaultEtherscanBaseURL
+			if pr, ok := poolRegistry.get(vLog.Address); ok {
+				tokenName = pr.info.TokenName
+				etherscanBaseURL = pr.info.EtherscanBaseURL
 			}
 
-			log.Printf("📊 New %s event detected from %s pool! TxHash: %s", eventType, tokenName, vLog.TxHash.Hex())
-
-			var err error
-			var notificationSent bool = false
-
-			switch eventType {
-			case "AllocateStablecoins":
-				// Parse AllocateStablecoins event
-				var event AllocateStablecoinsEvent
-				parseErr := contractABI.UnpackIntoInterface(&event, "AllocateStablecoins", vLog.Data)
-				if parseErr != nil {
-					log.Printf("❌ Failed to unpack AllocateStablecoins event: %v", parseErr)
-					// Send unparsed notification
-					err = sendUnparsedEventNotification(bot, config.TelegramChatID, eventType, tokenName, vLog.Address, vLog.TxHash.Hex(), vLog.Data)
-					notificationSent = true
-				} else {
-					// The allocator address is in the indexed topics
-					if len(vLog.Topics) > 1 {
-						event.Allocator = common.HexToAddress(vLog.Topics[1].Hex())
-					}
-
-					// Add pool identification info
-					event.PoolAddress = vLog.Address
-					event.TokenName = tokenName
-					event.YBURL = ybURL
-
-					// Send Telegram notification
-					err = sendTelegramNotification(bot, config.TelegramChatID, &event, vLog.TxHash.Hex())
-					notificationSent = true
-				}
+			handler, registered := lookupHandler(registry, vLog)
 
+			eventName := "Unknown"
+			if registered {
+				eventName = handler.Name()
 			}
+			eventsReceivedTotal.WithLabelValues(tokenName, eventName).Inc()
+			lastBlockSeen.WithLabelValues(tokenName).Set(float64(vLog.BlockNumber))
 
-			// Always report the result
-			if notificationSent {
-				if err != nil {
+			if !registered {
+				log.Printf("📊 New unrecognized event detected from %s pool! TxHash: %s", tokenName, vLog.TxHash.Hex())
+				if err := sendUnparsedEventNotification(bot, config.TelegramChatID, "Unknown", tokenName, vLog.Address, vLog.TxHash.Hex(), vLog.Data, etherscanBaseURL); err != nil {
 					log.Printf("❌ Failed to send Telegram notification: %v", err)
 				} else {
-					log.Printf("✅ %s notification sent successfully for %s pool", eventType, tokenName)
+					log.Printf("✅ Unparsed-event notification sent successfully for %s pool", tokenName)
 				}
+				continue
+			}
+
+			if !config.PoolEvents.isEnabled(tokenName, handler.Name()) {
+				log.Printf("🔕 %s event disabled for %s pool via POOL_EVENTS, dropping TxHash: %s", handler.Name(), tokenName, vLog.TxHash.Hex())
+				continue
+			}
+
+			log.Printf("📊 New %s event detected from %s pool! TxHash: %s", handler.Name(), tokenName, vLog.TxHash.Hex())
+			if err := handler.Handle(ctx, vLog, bot, config.TelegramChatID); err != nil {
+				log.Printf("❌ Failed to handle %s event: %v", handler.Name(), err)
 			}
 		}
 	}
 }
 
+// lookupHandler finds the handler registered for vLog's topic signature, regardless of whether
+// that event is enabled for the pool it came from. A miss here means the topic is genuinely
+// unrecognized (no handler was ever registered for it) and should fall through to the generic
+// unparsed-event notification; POOL_EVENTS enablement is checked separately by the caller so a
+// disabled-but-registered event can be dropped silently instead.
+func lookupHandler(registry *eventRegistry, vLog types.Log) (EventHandler, bool) {
+	if len(vLog.Topics) == 0 {
+		return nil, false
+	}
+
+	return registry.lookup(vLog.Topics[0])
+}
+
 func main() {
+	replayFromFlag := flag.String("replay-from", "", "manually replay AllocateStablecoins events from this block number, ignoring the checkpoint")
+	flag.Parse()
+
+	var replayFrom *uint64
+	if *replayFromFlag != "" {
+		v, err := strconv.ParseUint(*replayFromFlag, 10, 64)
+		if err != nil {
+			log.Fatalf("❌ Invalid --replay-from value: %v", err)
+		}
+		replayFrom = &v
+	}
+
 	log.Println("🚀 Starting YieldBasis Pool Cap Monitor...")
 
 	config, err := loadConfig()
@@ -356,11 +375,42 @@ func main() {
 		log.Fatalf("❌ Failed to load configuration: %v", err)
 	}
 
-	// Monitor events with retry logic
-	for {
-		err := monitorEvents(config)
-		log.Printf("❌ Monitoring stopped: %v", err)
+	cp, err := loadCheckpoint(config.CheckpointPath)
+	if err != nil {
+		log.Fatalf("❌ Failed to load checkpoint: %v", err)
+	}
+
+	if err := performStartupBackfill(config, cp, replayFrom); err != nil {
+		log.Printf("⚠️ Backfill failed, continuing with live monitoring only: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	metricsServer := startMetricsServer(config.MetricsAddr)
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("⚠️ Metrics server shutdown error: %v", err)
+		}
+	}()
+
+	// One confirmation queue per endpoint, shared across reconnects so a dropped WebSocket
+	// subscription re-checks whatever was still buffered instead of losing it.
+	queues := make(map[string]*confirmationQueue)
+
+	// Monitor events with retry logic, until SIGINT/SIGTERM tells us to shut down.
+	for ctx.Err() == nil {
+		if err := monitorEvents(ctx, config, queues, cp); err != nil {
+			log.Printf("❌ Monitoring stopped: %v", err)
+		}
+		if ctx.Err() != nil {
+			break
+		}
 		log.Println("🔄 Retrying in 10 seconds...")
 		time.Sleep(10 * time.Second)
 	}
+
+	log.Println("🛑 Shutdown signal received, stopping gracefully")
 }
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// defaultMetricsAddr is where /metrics is served when METRICS_ADDR is unset.
+const defaultMetricsAddr = ":9090"
+
+var (
+	eventsReceivedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ybcap_events_received_total",
+		Help: "Logs received per pool and event type, before filtering or parsing.",
+	}, []string{"pool", "event"})
+
+	eventsNotifiedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ybcap_events_notified_total",
+		Help: "Telegram notifications attempted per pool, event type, and outcome.",
+	}, []string{"pool", "event", "result"})
+
+	notifyErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ybcap_notify_errors_total",
+		Help: "Notification failures by reason.",
+	}, []string{"reason"})
+
+	lastBlockSeen = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ybcap_last_block_seen",
+		Help: "Block number of the most recent log seen per pool.",
+	}, []string{"pool"})
+
+	wsReconnectsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "ybcap_ws_reconnects_total",
+		Help: "WebSocket subscription reconnect attempts across all endpoints.",
+	})
+
+	// notifyLatencyBuckets covers everything from an immediate (unbuffered) notification up past
+	// a realistic worst-case MinConfirmations wait: AllocateStablecoins notifications are buffered
+	// until MinConfirmations blocks build on top of the log and are only checked once per
+	// confirmationPollInterval, so on a slow chain the receipt-to-notify gap can run to minutes,
+	// well beyond prometheus.DefBuckets' 10s ceiling.
+	notifyLatencyBuckets = []float64{1, 2, 5, 10, 15, 30, 60, 120, 300, 600, 1800, 3600}
+
+	notifyLatencySeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "ybcap_notify_latency_seconds",
+		Help:    "Time from log receipt to the corresponding Telegram notification being sent.",
+		Buckets: notifyLatencyBuckets,
+	})
+
+	pendingConfirmations = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ybcap_pending_confirmations",
+		Help: "Events currently buffered awaiting MinConfirmations, per pool address.",
+	}, []string{"pool"})
+)
+
+// startMetricsServer serves /metrics on addr in the background and returns the *http.Server so the
+// caller can shut it down gracefully alongside the rest of the process.
+func startMetricsServer(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("❌ Metrics server stopped: %v", err)
+		}
+	}()
+
+	log.Printf("📈 Metrics available at %s/metrics", addr)
+	return server
+}
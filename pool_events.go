@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// poolEventsConfig maps a pool's token name to the set of event names enabled for it, parsed
+// from POOL_EVENTS (e.g. "WBTC:AllocateStablecoins,Approval;TBTC:AllocateStablecoins"). A pool
+// absent from the map has every registered event enabled, so deployments that don't set
+// POOL_EVENTS keep monitoring everything the registry knows about.
+type poolEventsConfig map[string]map[string]bool
+
+// parsePoolEvents parses the POOL_EVENTS env value. An empty string enables every event for
+// every pool.
+func parsePoolEvents(raw string) (poolEventsConfig, error) {
+	cfg := make(poolEventsConfig)
+	if raw == "" {
+		return cfg, nil
+	}
+
+	for _, poolSpec := range strings.Split(raw, ";") {
+		poolSpec = strings.TrimSpace(poolSpec)
+		if poolSpec == "" {
+			continue
+		}
+
+		parts := strings.SplitN(poolSpec, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid POOL_EVENTS entry %q, expected TOKEN:Event1,Event2", poolSpec)
+		}
+
+		token := strings.TrimSpace(parts[0])
+		events := make(map[string]bool)
+		for _, name := range strings.Split(parts[1], ",") {
+			name = strings.TrimSpace(name)
+			if name != "" {
+				events[name] = true
+			}
+		}
+		cfg[token] = events
+	}
+
+	return cfg, nil
+}
+
+// isEnabled reports whether eventName should be notified on for tokenName.
+func (cfg poolEventsConfig) isEnabled(tokenName, eventName string) bool {
+	events, ok := cfg[tokenName]
+	if !ok {
+		return true
+	}
+	return events[eventName]
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePoolEvents(t *testing.T) {
+	t.Run("empty string enables everything", func(t *testing.T) {
+		cfg, err := parsePoolEvents("")
+		require.NoError(t, err)
+		assert.True(t, cfg.isEnabled("WBTC", "AllocateStablecoins"))
+		assert.True(t, cfg.isEnabled("WBTC", "Approval"))
+	})
+
+	t.Run("pool absent from POOL_EVENTS has every event enabled", func(t *testing.T) {
+		cfg, err := parsePoolEvents("WBTC:AllocateStablecoins")
+		require.NoError(t, err)
+		assert.True(t, cfg.isEnabled("TBTC", "AllocateStablecoins"))
+		assert.True(t, cfg.isEnabled("TBTC", "Approval"))
+	})
+
+	t.Run("event listed for a pool is enabled", func(t *testing.T) {
+		cfg, err := parsePoolEvents("WBTC:AllocateStablecoins,Approval")
+		require.NoError(t, err)
+		assert.True(t, cfg.isEnabled("WBTC", "AllocateStablecoins"))
+		assert.True(t, cfg.isEnabled("WBTC", "Approval"))
+	})
+
+	t.Run("event omitted for a pool that is listed is disabled", func(t *testing.T) {
+		cfg, err := parsePoolEvents("WBTC:AllocateStablecoins")
+		require.NoError(t, err)
+		assert.False(t, cfg.isEnabled("WBTC", "Approval"))
+	})
+
+	t.Run("multiple pools are parsed independently", func(t *testing.T) {
+		cfg, err := parsePoolEvents("WBTC:AllocateStablecoins,Approval;TBTC:AllocateStablecoins")
+		require.NoError(t, err)
+		assert.True(t, cfg.isEnabled("TBTC", "AllocateStablecoins"))
+		assert.False(t, cfg.isEnabled("TBTC", "Approval"))
+	})
+
+	t.Run("entry missing a colon errors", func(t *testing.T) {
+		_, err := parsePoolEvents("WBTC-AllocateStablecoins")
+		assert.Error(t, err)
+	})
+}
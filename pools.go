@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// defaultChainID and defaultEtherscanBaseURL describe Ethereum mainnet, the implicit chain for
+// pools that don't specify otherwise, which keeps existing single-chain deployments unchanged.
+const (
+	defaultChainID          = 1
+	defaultEtherscanBaseURL = "https://etherscan.io"
+)
+
+// unknownDecimals marks a pool whose decimals were not configured; resolveDecimals fetches and
+// caches them from the token contract's decimals() view the first time they're needed.
+const unknownDecimals = -1
+
+type PoolInfo struct {
+	Address          common.Address
+	TokenAddress     common.Address // zero if not configured; decimals then just fall back to 18
+	TokenName        string
+	YBURL            string
+	ChainID          int64
+	WsURL            string
+	EtherscanBaseURL string
+	Decimals         int
+}
+
+// poolDescriptor is the JSON shape read from POOLS_CONFIG_PATH, one entry per pool.
+type poolDescriptor struct {
+	Address          string `json:"address"`
+	TokenAddress     string `json:"token_address,omitempty"`
+	TokenName        string `json:"token_name"`
+	YBURL            string `json:"yb_url"`
+	ChainID          int64  `json:"chain_id"`
+	WsURL            string `json:"ws_url"`
+	EtherscanBaseURL string `json:"etherscan_base_url"`
+	Decimals         *int   `json:"decimals,omitempty"`
+}
+
+// loadPools resolves the pool list either from a POOLS_CONFIG_PATH JSON file (multi-chain, one
+// entry per pool with its own endpoint) or from the legacy POOL_ADDRESSES + per-pool
+// POOL_<TOKEN>_* env vars, which default every pool to defaultWsURL (mainnet, single endpoint).
+func loadPools(defaultWsURL string) ([]PoolInfo, error) {
+	if path := os.Getenv("POOLS_CONFIG_PATH"); path != "" {
+		return loadPoolsFromFile(path)
+	}
+	return loadPoolsFromEnv(defaultWsURL)
+}
+
+func loadPoolsFromFile(path string) ([]PoolInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pools config file: %v", err)
+	}
+
+	var descriptors []poolDescriptor
+	if err := json.Unmarshal(data, &descriptors); err != nil {
+		return nil, fmt.Errorf("failed to parse pools config file: %v", err)
+	}
+
+	pools := make([]PoolInfo, 0, len(descriptors))
+	for _, d := range descriptors {
+		if !common.IsHexAddress(d.Address) {
+			return nil, fmt.Errorf("invalid pool address in pools config: %s", d.Address)
+		}
+		if d.WsURL == "" {
+			return nil, fmt.Errorf("pool %s is missing ws_url", d.Address)
+		}
+		if d.TokenAddress != "" && !common.IsHexAddress(d.TokenAddress) {
+			return nil, fmt.Errorf("invalid token address in pools config: %s", d.TokenAddress)
+		}
+
+		decimals := unknownDecimals
+		if d.Decimals != nil {
+			decimals = *d.Decimals
+		}
+
+		chainID := d.ChainID
+		if chainID == 0 {
+			chainID = defaultChainID
+		}
+
+		etherscanBaseURL := d.EtherscanBaseURL
+		if etherscanBaseURL == "" {
+			etherscanBaseURL = defaultEtherscanBaseURL
+		}
+
+		var tokenAddress common.Address
+		if d.TokenAddress != "" {
+			tokenAddress = common.HexToAddress(d.TokenAddress)
+		}
+
+		pools = append(pools, PoolInfo{
+			Address:          common.HexToAddress(d.Address),
+			TokenAddress:     tokenAddress,
+			TokenName:        d.TokenName,
+			YBURL:            d.YBURL,
+			ChainID:          chainID,
+			WsURL:            d.WsURL,
+			EtherscanBaseURL: etherscanBaseURL,
+			Decimals:         decimals,
+		})
+	}
+
+	if len(pools) == 0 {
+		return nil, fmt.Errorf("no pools found in pools config file")
+	}
+
+	return pools, nil
+}
+
+// legacyPoolTokenMap and legacyPoolYBURLMap hold the token names and YieldBasis URLs of the
+// historical hardcoded mainnet WBTC/TBTC/CBBTC pools, looked up for every POOL_ADDRESSES entry when
+// POOLS_CONFIG_PATH is not set.
+var legacyPoolTokenMap = map[string]string{
+	"0x6095a220c5567360d459462a25b1ad5aead45204": "WBTC",
+	"0x2b513ebe7070cff91cf699a0bfe5075020c732ff": "TBTC",
+	"0xd6a1147666f6e4d7161caf436d9923d44d901112": "CBBTC",
+}
+
+var legacyPoolYBURLMap = map[string]string{
+	"0xa5bfb61af14afe7b81cac7fa4f7c4483dedc36df": "https://yieldbasis.com/market/0x6095a220C5567360d459462A25b1AD5aEAD45204",
+	"0x2b513ebe7070cff91cf699a0bfe5075020c732ff": "https://yieldbasis.com/market/0x2B513eBe7070Cff91cf699a0BFe5075020C732FF",
+	"0xd6a1147666f6e4d7161caf436d9923d44d901112": "https://yieldbasis.com/market/0xD6a1147666f6E4d7161caf436d9923D44d901112",
+}
+
+func loadPoolsFromEnv(defaultWsURL string) ([]PoolInfo, error) {
+	poolAddresses := os.Getenv("POOL_ADDRESSES")
+	if poolAddresses == "" {
+		// Default pools if not specified
+		poolAddresses = "0x6095a220C5567360d459462A25b1AD5aEAD45204,0x2B513eBe7070Cff91cf699a0BFe5075020C732FF,0xD6a1147666f6E4d7161caf436d9923D44d901112"
+	}
+
+	var pools []PoolInfo
+	for _, addr := range strings.Split(poolAddresses, ",") {
+		addr = strings.TrimSpace(addr)
+		if !common.IsHexAddress(addr) {
+			return nil, fmt.Errorf("invalid pool address: %s", addr)
+		}
+
+		tokenName := legacyPoolTokenMap[strings.ToLower(addr)]
+		if tokenName == "" {
+			tokenName = "UNKNOWN"
+		}
+
+		ybURL := legacyPoolYBURLMap[strings.ToLower(addr)]
+		if ybURL == "" {
+			ybURL = "https://yieldbasis.com"
+		}
+
+		pool, err := poolFromEnvOverrides(tokenName, addr, ybURL, defaultWsURL)
+		if err != nil {
+			return nil, err
+		}
+
+		pools = append(pools, pool)
+	}
+
+	if len(pools) == 0 {
+		return nil, fmt.Errorf("no valid pool addresses found")
+	}
+
+	return pools, nil
+}
+
+// poolFromEnvOverrides builds a PoolInfo for a legacy POOL_ADDRESSES entry, layering any
+// per-pool POOL_<TOKEN>_* overrides on top of the mainnet defaults.
+func poolFromEnvOverrides(tokenName, addr, ybURL, defaultWsURL string) (PoolInfo, error) {
+	envPrefix := "POOL_" + strings.ToUpper(tokenName) + "_"
+
+	chainID := int64(defaultChainID)
+	if v := os.Getenv(envPrefix + "CHAIN_ID"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return PoolInfo{}, fmt.Errorf("invalid %sCHAIN_ID: %v", envPrefix, err)
+		}
+		chainID = parsed
+	}
+
+	wsURL := os.Getenv(envPrefix + "WS_URL")
+	if wsURL == "" {
+		wsURL = defaultWsURL
+	}
+
+	etherscanBaseURL := os.Getenv(envPrefix + "ETHERSCAN_BASE_URL")
+	if etherscanBaseURL == "" {
+		etherscanBaseURL = defaultEtherscanBaseURL
+	}
+
+	decimals := unknownDecimals
+	if v := os.Getenv(envPrefix + "DECIMALS"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil {
+			return PoolInfo{}, fmt.Errorf("invalid %sDECIMALS: %v", envPrefix, err)
+		}
+		decimals = parsed
+	}
+
+	var tokenAddress common.Address
+	if v := os.Getenv(envPrefix + "TOKEN_ADDRESS"); v != "" {
+		if !common.IsHexAddress(v) {
+			return PoolInfo{}, fmt.Errorf("invalid %sTOKEN_ADDRESS: %s", envPrefix, v)
+		}
+		tokenAddress = common.HexToAddress(v)
+	}
+
+	return PoolInfo{
+		Address:          common.HexToAddress(addr),
+		TokenAddress:     tokenAddress,
+		TokenName:        tokenName,
+		YBURL:            ybURL,
+		ChainID:          chainID,
+		WsURL:            wsURL,
+		EtherscanBaseURL: etherscanBaseURL,
+		Decimals:         decimals,
+	}, nil
+}
+
+// groupPoolsByEndpoint buckets pools by WsURL so monitorEvents can spawn one subscription
+// goroutine per distinct RPC endpoint rather than one per pool.
+func groupPoolsByEndpoint(pools []PoolInfo) map[string][]PoolInfo {
+	groups := make(map[string][]PoolInfo)
+	for _, pool := range pools {
+		groups[pool.WsURL] = append(groups[pool.WsURL], pool)
+	}
+	return groups
+}
+
+// buildPoolInfoByAddress indexes pools by address for lookups that don't need a live client
+// (e.g. the startup backfill, which dials its own short-lived client per endpoint).
+func buildPoolInfoByAddress(pools []PoolInfo) map[common.Address]PoolInfo {
+	byAddress := make(map[common.Address]PoolInfo, len(pools))
+	for _, pool := range pools {
+		byAddress[pool.Address] = pool
+	}
+	return byAddress
+}
@@ -96,7 +96,7 @@ func TestTelegramNotifications(t *testing.T) {
 			t.Run(fmt.Sprintf("%sPool", event.TokenName), func(t *testing.T) {
 				t.Logf("📤 Sending test notification for %s pool...", event.TokenName)
 
-				err := sendTelegramNotification(bot, chatID, &event, testTxHashes[i])
+				err := sendTelegramNotification(bot, chatID, &event, testTxHashes[i], false, 18, defaultEtherscanBaseURL)
 				assert.NoError(t, err, "Failed to send %s test notification", event.TokenName)
 
 				t.Logf("✅ %s test notification sent successfully!", event.TokenName)
@@ -128,7 +128,7 @@ func TestTelegramChannelVsChatID(t *testing.T) {
 			t.Logf("Testing numeric chat ID: %s", chatID)
 		}
 
-		err := sendTelegramNotification(bot, chatID, &event, txHash)
+		err := sendTelegramNotification(bot, chatID, &event, txHash, false, 18, defaultEtherscanBaseURL)
 		assert.NoError(t, err, "Should handle chat ID format correctly")
 	})
 }